@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/aslotsu/search-sub/indexer"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultBatchInterval = 2 * time.Second
+	defaultWorkers       = 4
+)
+
+// pendingEvent is a decoded upsert or delete waiting to be flushed to the
+// search backend as part of a batch. msg is acked or nak'd once its line of
+// the batch response comes back, so one bad document never blocks the rest
+// of the batch.
+type pendingEvent struct {
+	id     string
+	delete bool
+	doc    indexer.Doc
+	msg    *nats.Msg
+}
+
+// batcher buffers documents and deletes for a single collection and
+// flushes them in bulk via a pool of lanes, trading per-message backend
+// calls for a single BulkImport/BulkDelete per batch.
+//
+// Each lane is a single goroutine with its own ordered buffer, and every id
+// is routed to the same lane for the life of the batcher (see laneFor), so
+// upserts and deletes for a given id are always handled by the same
+// single-threaded lane in arrival order. A flat worker pool reading off one
+// shared channel can't guarantee that: two workers racing their own buffers
+// and timers could flush a stale update after a newer one, or resurrect a
+// just-deleted document.
+type batcher struct {
+	collection string
+	idx        indexer.Indexer
+	hub        *hub
+
+	lanes []chan pendingEvent
+
+	batchSize int
+	interval  time.Duration
+}
+
+func newBatcher(collection string, idx indexer.Indexer, h *hub) *batcher {
+	return &batcher{
+		collection: collection,
+		idx:        idx,
+		hub:        h,
+		batchSize:  batchSizeFromEnv(),
+		interval:   batchIntervalFromEnv(),
+	}
+}
+
+func batchSizeFromEnv() int {
+	return envInt("INDEX_BATCH_SIZE", defaultBatchSize)
+}
+
+func batchIntervalFromEnv() time.Duration {
+	ms := envInt("INDEX_BATCH_INTERVAL_MS", int(defaultBatchInterval/time.Millisecond))
+	return time.Duration(ms) * time.Millisecond
+}
+
+func workerCountFromEnv() int {
+	return envInt("INDEX_WORKERS", defaultWorkers)
+}
+
+func envInt(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		log.Printf("⚠️ invalid %s %q, defaulting to %d", key, raw, fallback)
+		return fallback
+	}
+	return n
+}
+
+// start launches one goroutine per lane. lanes must be sized before any
+// enqueue call, so this must run before subscriptions start delivering.
+func (b *batcher) start(workers int) {
+	b.lanes = make([]chan pendingEvent, workers)
+	for i := range b.lanes {
+		b.lanes[i] = make(chan pendingEvent, b.batchSize*4)
+		go b.run(b.lanes[i])
+	}
+}
+
+// enqueueUpsert and enqueueDelete route an event to the lane owning id,
+// so every event for that id - upsert or delete - is ordered relative to
+// every other event for that same id.
+func (b *batcher) enqueueUpsert(id string, doc indexer.Doc, msg *nats.Msg) {
+	b.laneFor(id) <- pendingEvent{id: id, doc: doc, msg: msg}
+}
+
+func (b *batcher) enqueueDelete(id string, msg *nats.Msg) {
+	b.laneFor(id) <- pendingEvent{id: id, delete: true, msg: msg}
+}
+
+func (b *batcher) laneFor(id string) chan pendingEvent {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return b.lanes[h.Sum32()%uint32(len(b.lanes))]
+}
+
+func (b *batcher) run(lane chan pendingEvent) {
+	buf := make([]pendingEvent, 0, b.batchSize)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-lane:
+			buf = append(buf, e)
+			if len(buf) >= b.batchSize {
+				b.flush(buf)
+				buf = buf[:0]
+			}
+
+		case <-ticker.C:
+			if len(buf) > 0 {
+				b.flush(buf)
+				buf = buf[:0]
+			}
+		}
+	}
+}
+
+// flush collapses batch down to each id's most recent event - an upsert
+// followed by a delete for the same id within one flush window (or vice
+// versa) resolves to a single authoritative operation, applied once all
+// the messages that led to it are acked or nak'd together.
+func (b *batcher) flush(batch []pendingEvent) {
+	latest := make(map[string]pendingEvent, len(batch))
+	msgsByID := make(map[string][]*nats.Msg, len(batch))
+	order := make([]string, 0, len(batch))
+	for _, e := range batch {
+		if _, seen := latest[e.id]; !seen {
+			order = append(order, e.id)
+		}
+		latest[e.id] = e
+		msgsByID[e.id] = append(msgsByID[e.id], e.msg)
+	}
+
+	var docs []indexer.Doc
+	var docIDs []string
+	var deleteIDs []string
+	docByID := make(map[string]indexer.Doc, len(order))
+	for _, id := range order {
+		e := latest[id]
+		if e.delete {
+			deleteIDs = append(deleteIDs, id)
+		} else {
+			docs = append(docs, e.doc)
+			docIDs = append(docIDs, id)
+			docByID[id] = e.doc
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if len(docIDs) > 0 {
+		b.flushDocs(ctx, docIDs, docs, docByID, msgsByID)
+	}
+	if len(deleteIDs) > 0 {
+		b.flushDeletes(ctx, deleteIDs, msgsByID)
+	}
+}
+
+// flushDocs bulk-upserts the buffered documents through the backend's
+// BulkImport and acks/naks every message behind each id according to its
+// own entry in the result, so a single malformed document doesn't sink the
+// rest of the batch.
+func (b *batcher) flushDocs(ctx context.Context, ids []string, docs []indexer.Doc, docByID map[string]indexer.Doc, msgsByID map[string][]*nats.Msg) {
+	result, err := b.idx.BulkImport(ctx, b.collection, docs)
+	if err != nil {
+		log.Printf("❌ Failed to import %d documents into %s, will retry: %v", len(docs), b.collection, err)
+		for _, id := range ids {
+			nakAll(msgsByID[id])
+		}
+		return
+	}
+
+	for _, id := range result.Succeeded {
+		ackAll(msgsByID[id])
+		if b.hub != nil {
+			b.hub.publish(indexEvent{Type: "update", Data: docByID[id]})
+		}
+	}
+	for id, reason := range result.Failed {
+		log.Printf("❌ %s failed to import into %s: %s", id, b.collection, reason)
+		nakAll(msgsByID[id])
+	}
+
+	log.Printf("✅ Flushed batch of %d documents into %s", len(docs), b.collection)
+}
+
+// flushDeletes coalesces the buffered ids into a single bulk delete call
+// and acks/naks every message behind each id according to its own entry in
+// the result, so a single failing id doesn't sink the rest of the batch.
+func (b *batcher) flushDeletes(ctx context.Context, ids []string, msgsByID map[string][]*nats.Msg) {
+	result, err := b.idx.BulkDelete(ctx, b.collection, ids)
+	if err != nil {
+		log.Printf("❌ Failed to delete %d documents from %s, will retry: %v", len(ids), b.collection, err)
+		for _, id := range ids {
+			nakAll(msgsByID[id])
+		}
+		return
+	}
+
+	for _, id := range result.Succeeded {
+		ackAll(msgsByID[id])
+		if b.hub != nil {
+			b.hub.publish(indexEvent{Type: "delete", Data: map[string]interface{}{"id": id}})
+		}
+	}
+	for id, reason := range result.Failed {
+		log.Printf("❌ %s failed to delete from %s: %s", id, b.collection, reason)
+		nakAll(msgsByID[id])
+	}
+
+	log.Printf("✅ Flushed batch delete of %d documents from %s", len(ids), b.collection)
+}
+
+func ackAll(msgs []*nats.Msg) {
+	for _, msg := range msgs {
+		ackMsg(msg)
+	}
+}
+
+func nakAll(msgs []*nats.Msg) {
+	for _, msg := range msgs {
+		nakMsg(msg)
+	}
+}
+
+func ackMsg(msg *nats.Msg) {
+	if msg == nil {
+		return
+	}
+	if err := msg.Ack(); err != nil {
+		log.Printf("⚠️ Failed to ack message: %v", err)
+	}
+}
+
+func nakMsg(msg *nats.Msg) {
+	if msg == nil {
+		return
+	}
+	if err := msg.NakWithDelay(nakBackoff); err != nil {
+		log.Printf("⚠️ Failed to nak message: %v", err)
+	}
+}
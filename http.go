@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+)
+
+// serveHTTP starts the HTTP server exposing circuit breaker health and the
+// live SSE fan-out endpoints.
+func (s *SubscriberService) serveHTTP() {
+	addr := os.Getenv("HTTP_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/subscribe/users", s.handleSubscribe(entityUser))
+	mux.HandleFunc("/subscribe/posts", s.handleSubscribe(entityPost))
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("❌ HTTP server stopped: %v", err)
+		}
+	}()
+	log.Printf("🏥 HTTP server listening on %s", addr)
+}
+
+func (s *SubscriberService) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.idx.Health()); err != nil {
+		log.Printf("⚠️ failed to encode healthz response: %v", err)
+	}
+}
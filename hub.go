@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// indexEvent is the shape published to SSE clients whenever a document is
+// successfully written to or removed from a collection.
+type indexEvent struct {
+	Type string                 `json:"type"` // "update" or "delete"
+	Data map[string]interface{} `json:"data"`
+}
+
+// sseClientBufferSize bounds how many events a slow SSE client can fall
+// behind by before we drop it rather than let it back-pressure the hub.
+const sseClientBufferSize = 64
+
+// sseClient is a single connected SSE subscriber. filter holds the query
+// params (e.g. user_id, campus) the event's Data must match to be
+// delivered; an empty filter matches everything.
+type sseClient struct {
+	events chan indexEvent
+	filter map[string]string
+}
+
+// hub fans out index mutation events to every connected SSE client for one
+// collection, dropping events for clients that can't keep up rather than
+// blocking the publisher.
+type hub struct {
+	mu      sync.Mutex
+	clients map[*sseClient]struct{}
+}
+
+func newHub() *hub {
+	return &hub{clients: make(map[*sseClient]struct{})}
+}
+
+func (h *hub) subscribe(filter map[string]string) *sseClient {
+	c := &sseClient{events: make(chan indexEvent, sseClientBufferSize), filter: filter}
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+	return c
+}
+
+func (h *hub) unsubscribe(c *sseClient) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+	close(c.events)
+}
+
+func (h *hub) publish(e indexEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.clients {
+		if !matches(c.filter, e.Data) {
+			continue
+		}
+		select {
+		case c.events <- e:
+		default:
+			// slow consumer; drop this event rather than block the publisher.
+		}
+	}
+}
+
+func matches(filter map[string]string, data map[string]interface{}) bool {
+	for key, want := range filter {
+		got, ok := data[key]
+		if !ok {
+			return false
+		}
+		if fmt.Sprint(got) != want {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestMatches(t *testing.T) {
+	data := map[string]interface{}{"id": "1", "user_id": "u1", "campus": "downtown"}
+
+	tests := []struct {
+		name   string
+		filter map[string]string
+		want   bool
+	}{
+		{"empty filter matches everything", map[string]string{}, true},
+		{"matching single field", map[string]string{"user_id": "u1"}, true},
+		{"matching multiple fields", map[string]string{"user_id": "u1", "campus": "downtown"}, true},
+		{"mismatched field value", map[string]string{"campus": "uptown"}, false},
+		{"field missing from data", map[string]string{"missing": "x"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matches(tt.filter, data); got != tt.want {
+				t.Errorf("matches(%v, %v) = %v, want %v", tt.filter, data, got, tt.want)
+			}
+		})
+	}
+}
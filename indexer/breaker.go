@@ -0,0 +1,26 @@
+package indexer
+
+import (
+	"log"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+// newBreaker builds a circuit breaker named after the collection it guards,
+// so repeated backend failures for one collection stop hammering it without
+// affecting the other.
+func newBreaker(collection string) *gobreaker.CircuitBreaker {
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        collection,
+		MaxRequests: 1,
+		Interval:    60 * time.Second,
+		Timeout:     30 * time.Second,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= 5
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			log.Printf("🔌 circuit breaker %s: %s -> %s", name, from, to)
+		},
+	})
+}
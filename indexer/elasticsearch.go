@@ -0,0 +1,362 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/sony/gobreaker"
+)
+
+// ElasticsearchIndexer maps the same User/Post documents onto Elasticsearch
+// (or OpenSearch, which speaks the same REST API) _doc operations, using
+// the official client's _bulk support for batched writes.
+type ElasticsearchIndexer struct {
+	client   *elasticsearch.Client
+	breakers map[string]*gobreaker.CircuitBreaker
+}
+
+func NewElasticsearchIndexer(cfg Config) (*ElasticsearchIndexer, error) {
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: cfg.ElasticsearchAddresses,
+		APIKey:    cfg.ElasticsearchAPIKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create elasticsearch client: %w", err)
+	}
+
+	return &ElasticsearchIndexer{
+		client: client,
+		breakers: map[string]*gobreaker.CircuitBreaker{
+			CollectionUsers: newBreaker(CollectionUsers),
+			CollectionPosts: newBreaker(CollectionPosts),
+		},
+	}, nil
+}
+
+func (e *ElasticsearchIndexer) UpsertUser(ctx context.Context, doc Doc) error {
+	return e.index(ctx, CollectionUsers, doc)
+}
+
+func (e *ElasticsearchIndexer) DeleteUser(ctx context.Context, id string) error {
+	return e.delete(ctx, CollectionUsers, id)
+}
+
+func (e *ElasticsearchIndexer) UpsertPost(ctx context.Context, doc Doc) error {
+	return e.index(ctx, CollectionPosts, doc)
+}
+
+func (e *ElasticsearchIndexer) DeletePost(ctx context.Context, id string) error {
+	return e.delete(ctx, CollectionPosts, id)
+}
+
+func (e *ElasticsearchIndexer) index(ctx context.Context, collection string, doc Doc) error {
+	return e.indexInto(ctx, collection, collection, doc)
+}
+
+// indexInto writes doc into the given ES index, but keys the circuit
+// breaker by the logical collection rather than the index name so writes
+// into a versioned index (e.g. "users_1690000000" during a force-reindex)
+// still trip the same breaker as ordinary writes to "users".
+func (e *ElasticsearchIndexer) indexInto(ctx context.Context, indexName, collection string, doc Doc) error {
+	_, err := e.breakers[collection].Execute(func() (interface{}, error) {
+		return withRetry(ctx, func() (interface{}, error) {
+			body, err := json.Marshal(doc)
+			if err != nil {
+				return nil, err
+			}
+			res, err := esapi.IndexRequest{
+				Index:      indexName,
+				DocumentID: fmt.Sprint(doc["id"]),
+				Body:       bytes.NewReader(body),
+			}.Do(ctx, e.client)
+			if err != nil {
+				return nil, err
+			}
+			defer res.Body.Close()
+			if res.IsError() {
+				return nil, fmt.Errorf("index %s/%s: %s", indexName, doc["id"], res.Status())
+			}
+			return nil, nil
+		})
+	})
+	return err
+}
+
+func (e *ElasticsearchIndexer) delete(ctx context.Context, collection, id string) error {
+	return e.deleteFrom(ctx, collection, collection, id)
+}
+
+// deleteFrom removes id from the given ES index, keying the circuit
+// breaker by the logical collection rather than the index name so deletes
+// into a versioned index during a force-reindex still trip the same
+// breaker as ordinary deletes from "users"/"posts".
+func (e *ElasticsearchIndexer) deleteFrom(ctx context.Context, indexName, collection, id string) error {
+	_, err := e.breakers[collection].Execute(func() (interface{}, error) {
+		return withRetry(ctx, func() (interface{}, error) {
+			res, err := esapi.DeleteRequest{
+				Index:      indexName,
+				DocumentID: id,
+			}.Do(ctx, e.client)
+			if err != nil {
+				return nil, err
+			}
+			defer res.Body.Close()
+			if res.IsError() && res.StatusCode != 404 {
+				return nil, fmt.Errorf("delete %s/%s: %s", indexName, id, res.Status())
+			}
+			return nil, nil
+		})
+	})
+	return err
+}
+
+// BulkImport writes docs to collection via the _bulk API, one "index"
+// action per document, and reports per-document success from the response
+// items.
+func (e *ElasticsearchIndexer) BulkImport(ctx context.Context, collection string, docs []Doc) (BulkResult, error) {
+	var body bytes.Buffer
+	for _, doc := range docs {
+		meta := map[string]interface{}{
+			"index": map[string]interface{}{
+				"_index": collection,
+				"_id":    fmt.Sprint(doc["id"]),
+			},
+		}
+		metaLine, err := json.Marshal(meta)
+		if err != nil {
+			return BulkResult{}, err
+		}
+		docLine, err := json.Marshal(doc)
+		if err != nil {
+			return BulkResult{}, err
+		}
+		body.Write(metaLine)
+		body.WriteByte('\n')
+		body.Write(docLine)
+		body.WriteByte('\n')
+	}
+
+	raw, err := e.breakers[collection].Execute(func() (interface{}, error) {
+		return withRetry(ctx, func() (interface{}, error) {
+			res, err := esapi.BulkRequest{Body: bytes.NewReader(body.Bytes())}.Do(ctx, e.client)
+			if err != nil {
+				return nil, err
+			}
+			defer res.Body.Close()
+			if res.IsError() {
+				return nil, fmt.Errorf("bulk import into %s: %s", collection, res.Status())
+			}
+			var decoded bulkResponse
+			if err := json.NewDecoder(res.Body).Decode(&decoded); err != nil {
+				return nil, err
+			}
+			return decoded, nil
+		})
+	})
+	if err != nil {
+		return BulkResult{}, err
+	}
+
+	decoded := raw.(bulkResponse)
+	result := BulkResult{Failed: map[string]string{}}
+	for i, item := range decoded.Items {
+		id := fmt.Sprint(docs[i]["id"])
+		if item.Index.Status >= 200 && item.Index.Status < 300 {
+			result.Succeeded = append(result.Succeeded, id)
+			continue
+		}
+		result.Failed[id] = item.Index.Error.Reason
+	}
+	return result, nil
+}
+
+// BulkDelete removes ids from collection via the _bulk API, one "delete"
+// action per id, and reports per-document success from the response items.
+func (e *ElasticsearchIndexer) BulkDelete(ctx context.Context, collection string, ids []string) (BulkResult, error) {
+	var body bytes.Buffer
+	for _, id := range ids {
+		meta := map[string]interface{}{
+			"delete": map[string]interface{}{
+				"_index": collection,
+				"_id":    id,
+			},
+		}
+		metaLine, err := json.Marshal(meta)
+		if err != nil {
+			return BulkResult{}, err
+		}
+		body.Write(metaLine)
+		body.WriteByte('\n')
+	}
+
+	raw, err := e.breakers[collection].Execute(func() (interface{}, error) {
+		return withRetry(ctx, func() (interface{}, error) {
+			res, err := esapi.BulkRequest{Body: bytes.NewReader(body.Bytes())}.Do(ctx, e.client)
+			if err != nil {
+				return nil, err
+			}
+			defer res.Body.Close()
+			if res.IsError() {
+				return nil, fmt.Errorf("bulk delete from %s: %s", collection, res.Status())
+			}
+			var decoded bulkDeleteResponse
+			if err := json.NewDecoder(res.Body).Decode(&decoded); err != nil {
+				return nil, err
+			}
+			return decoded, nil
+		})
+	})
+	if err != nil {
+		return BulkResult{}, err
+	}
+
+	decoded := raw.(bulkDeleteResponse)
+	result := BulkResult{Failed: map[string]string{}}
+	for i, item := range decoded.Items {
+		id := ids[i]
+		if item.Delete.Status >= 200 && item.Delete.Status < 300 || item.Delete.Status == 404 {
+			result.Succeeded = append(result.Succeeded, id)
+			continue
+		}
+		result.Failed[id] = item.Delete.Error.Reason
+	}
+	return result, nil
+}
+
+type bulkDeleteResponse struct {
+	Items []struct {
+		Delete struct {
+			Status int `json:"status"`
+			Error  struct {
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"delete"`
+	} `json:"items"`
+}
+
+type bulkResponse struct {
+	Items []struct {
+		Index struct {
+			Status int `json:"status"`
+			Error  struct {
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"index"`
+	} `json:"items"`
+}
+
+// EnsureSchema creates the users/posts indices (with no explicit mapping, so
+// Elasticsearch maps fields dynamically) behind aliases of the same name if
+// they don't already exist. Elasticsearch tolerates adding new fields to an
+// existing mapping, so unlike Typesense there's no alias-swap path for
+// ordinary drift; forceReindex still rebuilds from scratch under a fresh
+// index name with the alias flipped once reindexing completes.
+func (e *ElasticsearchIndexer) EnsureSchema(ctx context.Context, forceReindex bool, replay ReplaySource) error {
+	targets := []struct {
+		alias      string
+		replayInto func(context.Context, func(Doc) error, func(string) error) error
+	}{
+		{CollectionUsers, replay.ReplayUsers},
+		{CollectionPosts, replay.ReplayPosts},
+	}
+
+	for _, target := range targets {
+		exists, err := e.aliasExists(ctx, target.alias)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !exists:
+			versioned := target.alias + "_v1"
+			if err := e.createIndex(ctx, versioned); err != nil {
+				return err
+			}
+			if err := e.swapAlias(ctx, target.alias, versioned); err != nil {
+				return err
+			}
+			log.Printf("🪣 provisioned index %s (alias %s)", versioned, target.alias)
+		case forceReindex:
+			versioned := fmt.Sprintf("%s_%d", target.alias, time.Now().Unix())
+			if err := e.createIndex(ctx, versioned); err != nil {
+				return err
+			}
+			count := 0
+			deleted := 0
+			if err := target.replayInto(ctx,
+				func(doc Doc) error {
+					if err := e.indexInto(ctx, versioned, target.alias, doc); err != nil {
+						return err
+					}
+					count++
+					return nil
+				},
+				func(id string) error {
+					if err := e.deleteFrom(ctx, versioned, target.alias, id); err != nil {
+						return err
+					}
+					deleted++
+					return nil
+				},
+			); err != nil {
+				return err
+			}
+			if err := e.swapAlias(ctx, target.alias, versioned); err != nil {
+				return err
+			}
+			log.Printf("✅ --force-reindex rebuilt %s into %s (%d documents, %d deletes applied)", target.alias, versioned, count, deleted)
+		}
+	}
+
+	return nil
+}
+
+func (e *ElasticsearchIndexer) aliasExists(ctx context.Context, alias string) (bool, error) {
+	res, err := esapi.IndicesExistsAliasRequest{Name: []string{alias}}.Do(ctx, e.client)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	return !res.IsError(), nil
+}
+
+func (e *ElasticsearchIndexer) createIndex(ctx context.Context, name string) error {
+	res, err := esapi.IndicesCreateRequest{Index: name}.Do(ctx, e.client)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("create index %s: %s", name, res.Status())
+	}
+	return nil
+}
+
+func (e *ElasticsearchIndexer) swapAlias(ctx context.Context, alias, index string) error {
+	actions := fmt.Sprintf(`{"actions":[{"remove":{"index":"%s*","alias":"%s"}},{"add":{"index":"%s","alias":"%s"}}]}`,
+		alias, alias, index, alias)
+	res, err := esapi.IndicesUpdateAliasesRequest{Body: strings.NewReader(actions)}.Do(ctx, e.client)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("swap alias %s -> %s: %s", alias, index, res.Status())
+	}
+	return nil
+}
+
+func (e *ElasticsearchIndexer) Health() map[string]string {
+	health := make(map[string]string, len(e.breakers))
+	for collection, b := range e.breakers {
+		health[collection] = b.State().String()
+	}
+	return health
+}
@@ -0,0 +1,115 @@
+// Package indexer abstracts the search backend behind a small interface so
+// search-sub can write to Typesense, Elasticsearch/OpenSearch, or future
+// backends without the subscription code knowing which one is in use.
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Doc is a decoded document ready to be written to the search backend,
+// keyed the same way SubscriberService's userToDocument/postToDocument
+// produce it.
+type Doc = map[string]interface{}
+
+// BulkResult reports, per document id, whether a bulk write succeeded.
+type BulkResult struct {
+	Succeeded []string
+	Failed    map[string]string // id -> error message
+}
+
+// ReplaySource lets an Indexer rebuild a collection from the system of
+// record (JetStream) when it needs to migrate a collection to a new
+// schema version. History is replayed in publish order, so onUpsert and
+// onDelete calls for the same document interleave correctly: a delete
+// replayed after its document's last upsert must remove it from the
+// collection being rebuilt rather than leaving a resurrected row behind.
+type ReplaySource interface {
+	ReplayUsers(ctx context.Context, onUpsert func(Doc) error, onDelete func(id string) error) error
+	ReplayPosts(ctx context.Context, onUpsert func(Doc) error, onDelete func(id string) error) error
+}
+
+// Indexer is implemented by each supported search backend.
+type Indexer interface {
+	UpsertUser(ctx context.Context, doc Doc) error
+	DeleteUser(ctx context.Context, id string) error
+	UpsertPost(ctx context.Context, doc Doc) error
+	DeletePost(ctx context.Context, id string) error
+
+	// BulkImport writes docs to the given collection ("users" or "posts")
+	// in a single request, returning the per-document outcome.
+	BulkImport(ctx context.Context, collection string, docs []Doc) (BulkResult, error)
+
+	// BulkDelete removes the given ids from collection in a single
+	// request, returning the per-document outcome.
+	BulkDelete(ctx context.Context, collection string, ids []string) (BulkResult, error)
+
+	// EnsureSchema provisions the backend's collections/indices if they
+	// don't exist, and migrates them via replay if forceReindex is set or,
+	// for backends that detect schema drift (Typesense), if they've drifted.
+	EnsureSchema(ctx context.Context, forceReindex bool, replay ReplaySource) error
+
+	// Health reports a short human-readable status per collection, used by
+	// the /healthz endpoint.
+	Health() map[string]string
+}
+
+const (
+	CollectionUsers = "users"
+	CollectionPosts = "posts"
+)
+
+// Config collects the settings every backend needs; each backend reads
+// only the fields relevant to it.
+type Config struct {
+	TypesenseUsersURL string
+	TypesensePostsURL string
+	TypesenseAPIKey   string
+
+	ElasticsearchAddresses []string
+	ElasticsearchAPIKey    string
+}
+
+// ConfigFromEnv builds a Config from the environment variables the two
+// shipped backends expect.
+func ConfigFromEnv() Config {
+	return Config{
+		TypesenseUsersURL: envOr("TYPESENSE_USERS_URL", "https://users2.exobook.ca:8108"),
+		TypesensePostsURL: envOr("TYPESENSE_POSTS_URL", "https://posts2.exobook.ca:8108"),
+		TypesenseAPIKey:   os.Getenv("TYPESENSE_API_KEY"),
+
+		ElasticsearchAddresses: splitNonEmpty(os.Getenv("ELASTICSEARCH_ADDRESSES"), ","),
+		ElasticsearchAPIKey:    os.Getenv("ELASTICSEARCH_API_KEY"),
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func splitNonEmpty(s, sep string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, sep)
+}
+
+// New builds the Indexer for the requested backend name ("typesense" or
+// "elasticsearch"), so future backends (Meilisearch, Bleve) can register
+// themselves here without touching the subscription code.
+func New(backend string, cfg Config) (Indexer, error) {
+	switch backend {
+	case "", "typesense":
+		return NewTypesenseIndexer(cfg), nil
+	case "elasticsearch":
+		return NewElasticsearchIndexer(cfg)
+	default:
+		return nil, fmt.Errorf("unknown SEARCH_BACKEND %q", backend)
+	}
+}
@@ -0,0 +1,60 @@
+package indexer
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	retryMaxAttempts = 4
+	retryBaseDelay   = 250 * time.Millisecond
+)
+
+// withRetry calls fn up to retryMaxAttempts times, backing off exponentially
+// with jitter between attempts. Only transient errors (429s and 5xxs) are
+// retried; anything else is returned to the caller immediately.
+func withRetry(ctx context.Context, fn func() (interface{}, error)) (interface{}, error) {
+	var lastErr error
+
+	for attempt := 0; attempt < retryMaxAttempts; attempt++ {
+		res, err := fn()
+		if err == nil {
+			return res, nil
+		}
+		if !isTransient(err) {
+			return nil, err
+		}
+		lastErr = err
+
+		if attempt == retryMaxAttempts-1 {
+			break
+		}
+
+		delay := retryBaseDelay * time.Duration(1<<uint(attempt))
+		jitter := time.Duration(rand.Int63n(int64(delay) + 1))
+		select {
+		case <-time.After(delay + jitter):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, lastErr
+}
+
+// isTransient reports whether err looks like a transient backend failure
+// worth retrying, based on the status codes it surfaces in its message.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, code := range []string{"429", "500", "502", "503", "504"} {
+		if strings.Contains(msg, code) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package indexer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil error", nil, false},
+		{"429 too many requests", errors.New("status: 429"), true},
+		{"500 internal server error", errors.New("request failed: 500 Internal Server Error"), true},
+		{"502 bad gateway", errors.New("502"), true},
+		{"503 service unavailable", errors.New("503"), true},
+		{"504 gateway timeout", errors.New("504"), true},
+		{"404 not found is not transient", errors.New("404"), false},
+		{"400 bad request is not transient", errors.New("400"), false},
+		{"unrelated error", errors.New("connection reset by peer"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/sony/gobreaker"
+	"github.com/typesense/typesense-go/typesense"
+	"github.com/typesense/typesense-go/typesense/api"
+	"github.com/typesense/typesense-go/typesense/api/pointer"
+
+	"github.com/aslotsu/search-sub/schema"
+)
+
+// TypesenseIndexer is the original backend: two independent Typesense
+// deployments, one per collection, each guarded by its own circuit
+// breaker.
+type TypesenseIndexer struct {
+	users *typesenseCollection
+	posts *typesenseCollection
+}
+
+type typesenseCollection struct {
+	name    string
+	client  *typesense.Client
+	breaker *gobreaker.CircuitBreaker
+}
+
+func NewTypesenseIndexer(cfg Config) *TypesenseIndexer {
+	return &TypesenseIndexer{
+		users: &typesenseCollection{
+			name:    CollectionUsers,
+			client:  typesense.NewClient(typesense.WithServer(cfg.TypesenseUsersURL), typesense.WithAPIKey(cfg.TypesenseAPIKey)),
+			breaker: newBreaker(CollectionUsers),
+		},
+		posts: &typesenseCollection{
+			name:    CollectionPosts,
+			client:  typesense.NewClient(typesense.WithServer(cfg.TypesensePostsURL), typesense.WithAPIKey(cfg.TypesenseAPIKey)),
+			breaker: newBreaker(CollectionPosts),
+		},
+	}
+}
+
+func (t *TypesenseIndexer) UpsertUser(ctx context.Context, doc Doc) error {
+	return upsert(ctx, t.users, doc)
+}
+
+func (t *TypesenseIndexer) DeleteUser(ctx context.Context, id string) error {
+	return del(ctx, t.users, id)
+}
+
+func (t *TypesenseIndexer) UpsertPost(ctx context.Context, doc Doc) error {
+	return upsert(ctx, t.posts, doc)
+}
+
+func (t *TypesenseIndexer) DeletePost(ctx context.Context, id string) error {
+	return del(ctx, t.posts, id)
+}
+
+func upsert(ctx context.Context, c *typesenseCollection, doc Doc) error {
+	_, err := c.breaker.Execute(func() (interface{}, error) {
+		return withRetry(ctx, func() (interface{}, error) {
+			return c.client.Collection(c.name).Documents().Upsert(ctx, doc)
+		})
+	})
+	return err
+}
+
+func del(ctx context.Context, c *typesenseCollection, id string) error {
+	_, err := c.breaker.Execute(func() (interface{}, error) {
+		return withRetry(ctx, func() (interface{}, error) {
+			return c.client.Collection(c.name).Document(id).Delete(ctx)
+		})
+	})
+	return err
+}
+
+func (t *TypesenseIndexer) BulkImport(ctx context.Context, collection string, docs []Doc) (BulkResult, error) {
+	c := t.collectionFor(collection)
+
+	items := make([]interface{}, len(docs))
+	for i, d := range docs {
+		items[i] = d
+	}
+
+	raw, err := c.breaker.Execute(func() (interface{}, error) {
+		return withRetry(ctx, func() (interface{}, error) {
+			return c.client.Collection(c.name).Documents().Import(ctx, items, &api.ImportDocumentsParams{
+				Action: pointer.String("upsert"),
+			})
+		})
+	})
+	if err != nil {
+		return BulkResult{}, err
+	}
+
+	responses := raw.([]*api.ImportDocumentResponse)
+	result := BulkResult{Failed: map[string]string{}}
+	for i, d := range docs {
+		id := fmt.Sprint(d["id"])
+		if i < len(responses) && responses[i].Success {
+			result.Succeeded = append(result.Succeeded, id)
+			continue
+		}
+		result.Failed[id] = importErrorMessage(responses, i)
+	}
+	return result, nil
+}
+
+// BulkDelete removes ids from collection with a single filter_by:=id:[...]
+// delete request instead of one HTTP call per id.
+func (t *TypesenseIndexer) BulkDelete(ctx context.Context, collection string, ids []string) (BulkResult, error) {
+	c := t.collectionFor(collection)
+
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = id
+	}
+	filter := fmt.Sprintf("id:=[%s]", strings.Join(quoted, ","))
+
+	numDeleted, err := c.breaker.Execute(func() (interface{}, error) {
+		return withRetry(ctx, func() (interface{}, error) {
+			return c.client.Collection(c.name).Documents().Delete(ctx, &api.DeleteDocumentsParams{FilterBy: pointer.String(filter)})
+		})
+	})
+	if err != nil {
+		return BulkResult{}, err
+	}
+
+	result := BulkResult{Failed: map[string]string{}}
+	if numDeleted.(int) == len(ids) {
+		result.Succeeded = ids
+		return result, nil
+	}
+
+	// Typesense's filter_by delete doesn't report which ids failed, only a
+	// count, so treat a partial delete as a single batch-level error rather
+	// than guessing which ids it missed.
+	for _, id := range ids {
+		result.Failed[id] = fmt.Sprintf("bulk delete reported %d of %d removed", numDeleted.(int), len(ids))
+	}
+	return result, nil
+}
+
+func importErrorMessage(responses []*api.ImportDocumentResponse, i int) string {
+	if i >= len(responses) || responses[i].Error == "" {
+		return "unknown error"
+	}
+	return responses[i].Error
+}
+
+func (t *TypesenseIndexer) EnsureSchema(ctx context.Context, forceReindex bool, replay ReplaySource) error {
+	targets := []struct {
+		collection *typesenseCollection
+		wanted     *api.CollectionSchema
+		replayInto func(context.Context, func(Doc) error, func(string) error) error
+	}{
+		{t.users, schema.Users(), replay.ReplayUsers},
+		{t.posts, schema.Posts(), replay.ReplayPosts},
+	}
+
+	for _, target := range targets {
+		result, err := schema.Ensure(ctx, target.collection.client, target.wanted)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case result.Created:
+			log.Printf("🪣 provisioned collection %s", target.wanted.Name)
+		case result.Drifted:
+			log.Printf("🔁 schema drift detected for %s, migrating to %s", target.wanted.Name, result.VersionedName)
+			if err := t.reindexVersion(ctx, target.collection, target.wanted.Name, result.VersionedName, target.replayInto); err != nil {
+				return err
+			}
+		case forceReindex:
+			versioned, err := schema.Migrate(ctx, target.collection.client, target.wanted)
+			if err != nil {
+				return err
+			}
+			log.Printf("🔁 --force-reindex requested for %s, rebuilding %s", target.wanted.Name, versioned)
+			if err := t.reindexVersion(ctx, target.collection, target.wanted.Name, versioned, target.replayInto); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (t *TypesenseIndexer) reindexVersion(ctx context.Context, c *typesenseCollection, collection, versionedName string, replayInto func(context.Context, func(Doc) error, func(string) error) error) error {
+	count := 0
+	deleted := 0
+	err := replayInto(ctx,
+		func(doc Doc) error {
+			if _, err := c.client.Collection(versionedName).Documents().Upsert(ctx, doc); err != nil {
+				return err
+			}
+			count++
+			return nil
+		},
+		func(id string) error {
+			if _, err := c.client.Collection(versionedName).Document(id).Delete(ctx); err != nil {
+				// The id may have been deleted before its upsert fell within
+				// the stream's retention window, so it was never replayed
+				// into this version; that's not an error.
+				if isNotFoundErr(err) {
+					return nil
+				}
+				return err
+			}
+			deleted++
+			return nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("reindex into %s: %w", versionedName, err)
+	}
+
+	log.Printf("🔁 reindexed %d documents into %s (%d deletes applied)", count, versionedName, deleted)
+	if err := schema.PromoteAlias(ctx, c.client, collection, versionedName); err != nil {
+		return err
+	}
+	log.Printf("✅ promoted %s alias to %s", collection, versionedName)
+	return nil
+}
+
+func isNotFoundErr(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "404")
+}
+
+func (t *TypesenseIndexer) Health() map[string]string {
+	return map[string]string{
+		CollectionUsers: t.users.breaker.State().String(),
+		CollectionPosts: t.posts.breaker.State().String(),
+	}
+}
+
+func (t *TypesenseIndexer) collectionFor(collection string) *typesenseCollection {
+	if collection == CollectionUsers {
+		return t.users
+	}
+	return t.posts
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type entity string
+
+const (
+	entityUser entity = "user"
+	entityPost entity = "post"
+
+	serviceName = "search-sub"
+
+	streamUsers = "USERS"
+	streamPosts = "POSTS"
+)
+
+// subjectUpsert returns the subject that create/update events for an entity
+// are published to, e.g. "search-sub.request.user.upsert".
+func subjectUpsert(e entity) string {
+	return fmt.Sprintf("%s.request.%s.upsert", serviceName, e)
+}
+
+// subjectDelete returns the subject that delete events for an entity are
+// published to, e.g. "search-sub.request.user.delete".
+func subjectDelete(e entity) string {
+	return fmt.Sprintf("%s.request.%s.delete", serviceName, e)
+}
+
+// subjectPullAll returns the wildcard replay subject covering both the
+// upsert and delete variants for an entity. A single subscription against
+// this filter sees both in the stream's publish order, which a rebuild
+// needs to correctly apply a delete that comes after its document's last
+// upsert (and vice versa).
+func subjectPullAll(e entity) string {
+	return fmt.Sprintf("%s.pull.%s.>", serviceName, e)
+}
+
+// ensureStreams makes sure the USERS and POSTS streams exist so messages
+// published before this service starts aren't lost. Retention and max age
+// are configurable so operators can tune how long replay history is kept.
+func ensureStreams(js nats.JetStreamContext) error {
+	maxAge := streamMaxAge()
+	retention := streamRetention()
+
+	streams := []*nats.StreamConfig{
+		{
+			Name:      streamUsers,
+			Subjects:  []string{"search-sub.request.user.>", "search-sub.pull.user.>"},
+			Retention: retention,
+			MaxAge:    maxAge,
+			Storage:   nats.FileStorage,
+		},
+		{
+			Name:      streamPosts,
+			Subjects:  []string{"search-sub.request.post.>", "search-sub.pull.post.>"},
+			Retention: retention,
+			MaxAge:    maxAge,
+			Storage:   nats.FileStorage,
+		},
+	}
+
+	for _, cfg := range streams {
+		if _, err := js.StreamInfo(cfg.Name); err != nil {
+			if _, err := js.AddStream(cfg); err != nil {
+				return fmt.Errorf("create stream %s: %w", cfg.Name, err)
+			}
+			log.Printf("🪣 created stream %s", cfg.Name)
+			continue
+		}
+		if _, err := js.UpdateStream(cfg); err != nil {
+			return fmt.Errorf("update stream %s: %w", cfg.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func streamMaxAge() time.Duration {
+	raw := os.Getenv("STREAM_MAX_AGE")
+	if raw == "" {
+		return 7 * 24 * time.Hour
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Printf("⚠️ invalid STREAM_MAX_AGE %q, defaulting to 7 days: %v", raw, err)
+		return 7 * 24 * time.Hour
+	}
+	return d
+}
+
+func streamRetention() nats.RetentionPolicy {
+	switch os.Getenv("STREAM_RETENTION") {
+	case "interest":
+		return nats.InterestPolicy
+	case "workqueue":
+		return nats.WorkQueuePolicy
+	default:
+		return nats.LimitsPolicy
+	}
+}
+
+// durableName returns the durable consumer name for an entity's upsert or
+// delete subscription, namespaced per stream so both can coexist.
+func durableName(e entity, action string) string {
+	return fmt.Sprintf("%s-%s-%s", serviceName, e, action)
+}
+
+// queueGroupName returns the queue group every instance of this service
+// joins for an entity's upsert or delete subscription, so multiple
+// instances can share the durable consumer and each message is delivered
+// to exactly one of them instead of all of them.
+func queueGroupName(e entity, action string) string {
+	return durableName(e, action) + "-workers"
+}
+
+// replay rebuilds the configured search index by binding a fresh
+// ephemeral consumer that starts delivery at the requested point and
+// tailing it until the stream is drained.
+//
+// target is one of:
+//   - "all": start from the beginning of the stream (DeliverAll)
+//   - an RFC3339 timestamp: start from the first message at or after it
+//   - a bare integer: start from that stream sequence number
+func (s *SubscriberService) replay(target string) error {
+	for _, e := range []entity{entityUser, entityPost} {
+		opts := []nats.SubOpt{nats.BindStream(streamFor(e))}
+
+		switch {
+		case target == "all":
+			opts = append(opts, nats.DeliverAll())
+		default:
+			if seq, err := strconv.ParseUint(target, 10, 64); err == nil {
+				opts = append(opts, nats.StartSequence(seq))
+			} else if ts, err := time.Parse(time.RFC3339, target); err == nil {
+				opts = append(opts, nats.StartTime(ts))
+			} else {
+				return fmt.Errorf("invalid replay target %q: must be \"all\", an RFC3339 time, or a sequence number", target)
+			}
+		}
+
+		sub, err := s.js.SubscribeSync(subjectPullAll(e), opts...)
+		if err != nil {
+			return fmt.Errorf("bind replay consumer for %s: %w", e, err)
+		}
+		log.Printf("🔁 replaying %s from %s", e, target)
+
+		if err := s.drainReplay(e, sub); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func streamFor(e entity) string {
+	if e == entityUser {
+		return streamUsers
+	}
+	return streamPosts
+}
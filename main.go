@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -10,7 +10,8 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/nats-io/nats.go"
-	"github.com/typesense/typesense-go/typesense"
+
+	"github.com/aslotsu/search-sub/indexer"
 )
 
 type Post struct {
@@ -48,9 +49,15 @@ type User struct {
 }
 
 type SubscriberService struct {
-	natsConn      *nats.Conn
-	usersClient   *typesense.Client
-	postsClient   *typesense.Client
+	natsConn *nats.Conn
+	js       nats.JetStreamContext
+	idx      indexer.Indexer
+
+	usersBatcher *batcher
+	postsBatcher *batcher
+
+	usersHub *hub
+	postsHub *hub
 }
 
 func writeCredsFileFromEnv() string {
@@ -73,6 +80,9 @@ func main() {
 	start := time.Now()
 	_ = godotenv.Load()
 
+	replayFrom := flag.String("replay-from", "", `replay mode: "all", a RFC3339 timestamp, or a stream sequence number`)
+	flag.Parse()
+
 	// Connect to NATS
 	credsPath := writeCredsFileFromEnv()
 	nc, err := nats.Connect(`connect.ngs.global`, nats.UserCredentials(credsPath))
@@ -82,181 +92,72 @@ func main() {
 	defer nc.Drain()
 	log.Println("NATS connection time:", time.Since(start))
 
-	// Connect to Typesense instances
-	usersClient := typesense.NewClient(
-		typesense.WithServer("https://users2.exobook.ca:8108"),
-		typesense.WithAPIKey(os.Getenv(
-			"TYPESENSE_API_KEY",
-		)),
-	)
+	js, err := nc.JetStream()
+	if err != nil {
+		log.Fatalf("failed to get JetStream context: %v", err)
+	}
 
-	postsClient := typesense.NewClient(
-		typesense.WithServer("https://posts2.exobook.ca:8108"), // Adjust as needed
-		typesense.WithAPIKey(os.Getenv(
-			"TYPESENSE_API_KEY",
-		)),
-	)
+	if err := ensureStreams(js); err != nil {
+		log.Fatalf("failed to provision streams: %v", err)
+	}
+
+	// Build the configured search backend (Typesense by default).
+	idx, err := indexer.New(os.Getenv("SEARCH_BACKEND"), indexer.ConfigFromEnv())
+	if err != nil {
+		log.Fatalf("failed to build search backend: %v", err)
+	}
+
+	usersHub := newHub()
+	postsHub := newHub()
 
 	// Create subscriber service
 	service := &SubscriberService{
-		natsConn:    nc,
-		usersClient: usersClient,
-		postsClient: postsClient,
+		natsConn:     nc,
+		js:           js,
+		idx:          idx,
+		usersBatcher: newBatcher(indexer.CollectionUsers, idx, usersHub),
+		postsBatcher: newBatcher(indexer.CollectionPosts, idx, postsHub),
+		usersHub:     usersHub,
+		postsHub:     postsHub,
 	}
 
+	if err := idx.EnsureSchema(context.Background(), *forceReindexFlag, service); err != nil {
+		log.Fatalf("failed to ensure collection schemas: %v", err)
+	}
+
+	if *replayFrom != "" {
+		if err := service.replay(*replayFrom); err != nil {
+			log.Fatalf("replay failed: %v", err)
+		}
+		return
+	}
+
+	workers := workerCountFromEnv()
+	service.usersBatcher.start(workers)
+	service.postsBatcher.start(workers)
+	service.serveHTTP()
+
 	// Subscribe to all events
 	service.setupSubscriptions()
 
-	fmt.Println("🚀 Listening for events on NATS...")
-	fmt.Println("📝 Subscribed to: users.created, users.updated, users.deleted")
-	fmt.Println("📄 Subscribed to: posts.upsert, posts.deleted")
-	
+	fmt.Println("🚀 Listening for events on NATS JetStream...")
+	fmt.Println("📝 Subscribed to:", subjectUpsert(entityUser), subjectDelete(entityUser))
+	fmt.Println("📄 Subscribed to:", subjectUpsert(entityPost), subjectDelete(entityPost))
+
 	select {} // block forever
 }
 
 func (s *SubscriberService) setupSubscriptions() {
 	// Users events
-	s.subscribeToUserCreated()
-	s.subscribeToUserUpdated()
+	s.subscribeToUserUpserts()
 	s.subscribeToUserDeleted()
-	
+
 	// Posts events
 	s.subscribeToPostUpsert()
 	s.subscribeToPostDeleted()
 }
 
-func (s *SubscriberService) subscribeToUserCreated() {
-	_, err := s.natsConn.Subscribe("users.created", func(msg *nats.Msg) {
-		log.Println("📥 New user created")
-		
-		var user User
-		if err := json.Unmarshal(msg.Data, &user); err != nil {
-			log.Printf("❌ Failed to unmarshal user: %v", err)
-			return
-		}
-
-		document := s.userToDocument(user)
-		
-		_, err := s.usersClient.Collection("users").Documents().Create(context.Background(), document)
-		if err != nil {
-			log.Printf("❌ Failed to create user in Typesense: %v", err)
-			return
-		}
-		
-		log.Printf("✅ Created user in search index: %s (%s)", user.Id, user.Name)
-	})
-	
-	if err != nil {
-		log.Fatalf("Failed to subscribe to users.created: %v", err)
-	}
-}
-
-func (s *SubscriberService) subscribeToUserUpdated() {
-	_, err := s.natsConn.Subscribe("users.updated", func(msg *nats.Msg) {
-		log.Println("📥 User updated")
-		
-		var user User
-		if err := json.Unmarshal(msg.Data, &user); err != nil {
-			log.Printf("❌ Failed to unmarshal user: %v", err)
-			return
-		}
-
-		document := s.userToDocument(user)
-		
-		_, err := s.usersClient.Collection("users").Documents().Upsert(context.Background(), document)
-		if err != nil {
-			log.Printf("❌ Failed to update user in Typesense: %v", err)
-			return
-		}
-		
-		log.Printf("✅ Updated user in search index: %s (%s)", user.Id, user.Name)
-	})
-	
-	if err != nil {
-		log.Fatalf("Failed to subscribe to users.updated: %v", err)
-	}
-}
-
-func (s *SubscriberService) subscribeToUserDeleted() {
-	_, err := s.natsConn.Subscribe("users.deleted", func(msg *nats.Msg) {
-		log.Println("📥 User deleted")
-		
-		// For deletes, we might just get the ID
-		var deleteEvent struct {
-			Id string `json:"id"`
-		}
-		if err := json.Unmarshal(msg.Data, &deleteEvent); err != nil {
-			log.Printf("❌ Failed to unmarshal delete event: %v", err)
-			return
-		}
-
-		_, err := s.usersClient.Collection("users").Document(deleteEvent.Id).Delete(context.Background())
-		if err != nil {
-			log.Printf("❌ Failed to delete user from Typesense: %v", err)
-			return
-		}
-		
-		log.Printf("✅ Deleted user from search index: %s", deleteEvent.Id)
-	})
-	
-	if err != nil {
-		log.Fatalf("Failed to subscribe to users.deleted: %v", err)
-	}
-}
-
-func (s *SubscriberService) subscribeToPostUpsert() {
-	_, err := s.natsConn.Subscribe("posts.upsert", func(msg *nats.Msg) {
-		log.Println("📥 Post upserted")
-		
-		var post Post
-		if err := json.Unmarshal(msg.Data, &post); err != nil {
-			log.Printf("❌ Failed to unmarshal post: %v", err)
-			return
-		}
-
-		document := s.postToDocument(post)
-		
-		_, err := s.postsClient.Collection("posts").Documents().Upsert(context.Background(), document)
-		if err != nil {
-			log.Printf("❌ Failed to upsert post in Typesense: %v", err)
-			return
-		}
-		
-		log.Printf("✅ Upserted post in search index: %s", post.Id)
-	})
-	
-	if err != nil {
-		log.Fatalf("Failed to subscribe to posts.upsert: %v", err)
-	}
-}
-
-func (s *SubscriberService) subscribeToPostDeleted() {
-	_, err := s.natsConn.Subscribe("posts.deleted", func(msg *nats.Msg) {
-		log.Println("📥 Post deleted")
-		
-		var deleteEvent struct {
-			Id string `json:"id"`
-		}
-		if err := json.Unmarshal(msg.Data, &deleteEvent); err != nil {
-			log.Printf("❌ Failed to unmarshal delete event: %v", err)
-			return
-		}
-
-		_, err := s.postsClient.Collection("posts").Document(deleteEvent.Id).Delete(context.Background())
-		if err != nil {
-			log.Printf("❌ Failed to delete post from Typesense: %v", err)
-			return
-		}
-		
-		log.Printf("✅ Deleted post from search index: %s", deleteEvent.Id)
-	})
-	
-	if err != nil {
-		log.Fatalf("Failed to subscribe to posts.deleted: %v", err)
-	}
-}
-
-// Helper functions to convert structs to Typesense documents
+// Helper functions to convert structs to search backend documents
 func (s *SubscriberService) userToDocument(user User) map[string]interface{} {
 	return map[string]interface{}{
 		"id":           user.Id,
@@ -293,4 +194,4 @@ func (s *SubscriberService) postToDocument(post Post) map[string]interface{} {
 		"created_at":     post.CreatedAt.Unix(),
 		"updated_at":     post.UpdatedAt.Unix(),
 	}
-}
\ No newline at end of file
+}
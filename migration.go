@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/aslotsu/search-sub/indexer"
+)
+
+// forceReindexFlag exposes --force-reindex so operators can trigger the
+// alias-swap migration path on demand, even without a schema change.
+var forceReindexFlag = flag.Bool("force-reindex", false, "rebuild collections from JetStream history and flip their aliases")
+
+// ReplayUsers and ReplayPosts let the configured Indexer rebuild a
+// collection from JetStream history during a schema migration, without
+// needing to know anything about NATS itself. Both upserts and deletes are
+// replayed in publish order, so a delete that comes after its document's
+// last upsert correctly removes it from the collection being rebuilt.
+func (s *SubscriberService) ReplayUsers(ctx context.Context, onUpsert func(indexer.Doc) error, onDelete func(id string) error) error {
+	return s.replayInto(entityUser, onUpsert, onDelete)
+}
+
+func (s *SubscriberService) ReplayPosts(ctx context.Context, onUpsert func(indexer.Doc) error, onDelete func(id string) error) error {
+	return s.replayInto(entityPost, onUpsert, onDelete)
+}
+
+func (s *SubscriberService) replayInto(e entity, onUpsert func(indexer.Doc) error, onDelete func(id string) error) error {
+	sub, err := s.js.SubscribeSync(subjectPullAll(e), nats.BindStream(streamFor(e)), nats.DeliverAll())
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		msg, err := sub.NextMsg(2 * time.Second)
+		if err != nil {
+			if errors.Is(err, nats.ErrTimeout) {
+				return nil
+			}
+			return err
+		}
+
+		if strings.HasSuffix(msg.Subject, ".delete") {
+			id, err := decodeDeleteID(msg.Data)
+			if err != nil {
+				continue
+			}
+			if err := onDelete(id); err != nil {
+				return err
+			}
+			continue
+		}
+
+		doc, err := s.decodeDocument(e, msg.Data)
+		if err != nil {
+			continue
+		}
+		if err := onUpsert(doc); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *SubscriberService) decodeDocument(e entity, data []byte) (map[string]interface{}, error) {
+	switch e {
+	case entityUser:
+		var user User
+		if err := json.Unmarshal(data, &user); err != nil {
+			return nil, err
+		}
+		return s.userToDocument(user), nil
+	case entityPost:
+		var post Post
+		if err := json.Unmarshal(data, &post); err != nil {
+			return nil, err
+		}
+		return s.postToDocument(post), nil
+	default:
+		return nil, errors.New("unknown entity")
+	}
+}
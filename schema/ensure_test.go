@@ -0,0 +1,126 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/typesense/typesense-go/typesense"
+	"github.com/typesense/typesense-go/typesense/api"
+)
+
+// fakeTypesense is a minimal in-memory stand-in for the bits of the
+// Typesense HTTP API Ensure/Migrate talk to (collections and aliases),
+// just enough to exercise Ensure across repeated calls the way a
+// restarting service would.
+type fakeTypesense struct {
+	mu          sync.Mutex
+	aliases     map[string]string
+	collections map[string]api.CollectionResponse
+}
+
+func newFakeTypesense() *fakeTypesense {
+	return &fakeTypesense{
+		aliases:     map[string]string{},
+		collections: map[string]api.CollectionResponse{},
+	}
+}
+
+func (f *fakeTypesense) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/aliases/"):
+		name := strings.TrimPrefix(r.URL.Path, "/aliases/")
+		target, ok := f.aliases[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(api.ApiResponse{})
+			return
+		}
+		json.NewEncoder(w).Encode(api.CollectionAlias{Name: &name, CollectionName: target})
+
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/aliases/"):
+		name := strings.TrimPrefix(r.URL.Path, "/aliases/")
+		var body api.CollectionAliasSchema
+		json.NewDecoder(r.Body).Decode(&body)
+		f.aliases[name] = body.CollectionName
+		json.NewEncoder(w).Encode(api.CollectionAlias{Name: &name, CollectionName: body.CollectionName})
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/collections/"):
+		name := strings.TrimPrefix(r.URL.Path, "/collections/")
+		col, ok := f.collections[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(api.ApiResponse{})
+			return
+		}
+		json.NewEncoder(w).Encode(col)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/collections":
+		var schema api.CollectionSchema
+		json.NewDecoder(r.Body).Decode(&schema)
+		if _, exists := f.collections[schema.Name]; exists {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(api.ApiResponse{})
+			return
+		}
+		resp := api.CollectionResponse{Name: schema.Name, Fields: schema.Fields}
+		f.collections[schema.Name] = resp
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(resp)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(api.ApiResponse{})
+	}
+}
+
+func TestEnsureAcrossRestarts(t *testing.T) {
+	fake := newFakeTypesense()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	client := typesense.NewClient(typesense.WithServer(srv.URL), typesense.WithAPIKey("test"))
+
+	desired := Users()
+
+	result, err := Ensure(context.Background(), client, desired)
+	if err != nil {
+		t.Fatalf("first Ensure: %v", err)
+	}
+	if !result.Created {
+		t.Fatalf("first Ensure: want Created=true, got %+v", result)
+	}
+	if got := fake.aliases[desired.Name]; got != "users_v1" {
+		t.Fatalf("expected alias %s -> users_v1, got %s", desired.Name, got)
+	}
+
+	// A second Ensure call, as happens on every subsequent restart, must
+	// see the alias and do nothing rather than 404 on the alias name and
+	// try to recreate "users_v1" (which previously crashed the service).
+	result, err = Ensure(context.Background(), client, desired)
+	if err != nil {
+		t.Fatalf("second Ensure (restart) returned an error instead of a no-op: %v", err)
+	}
+	if result.Created || result.Drifted {
+		t.Fatalf("second Ensure: want a no-op, got %+v", result)
+	}
+
+	// A third restart after the schema has drifted must migrate to v2, not
+	// re-attempt v1.
+	drifted := *desired
+	drifted.Fields = append(drifted.Fields, api.Field{Name: "extra", Type: "string"})
+	result, err = Ensure(context.Background(), client, &drifted)
+	if err != nil {
+		t.Fatalf("third Ensure (drift): %v", err)
+	}
+	if !result.Drifted || result.VersionedName != "users_v2" {
+		t.Fatalf("third Ensure: want Drifted into users_v2, got %+v", result)
+	}
+}
@@ -0,0 +1,222 @@
+// Package schema declares the Typesense collection schemas expected by
+// search-sub, mirroring the User and Post structs in the main package, and
+// knows how to provision or migrate them on startup.
+package schema
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/typesense/typesense-go/typesense"
+	"github.com/typesense/typesense-go/typesense/api"
+	"github.com/typesense/typesense-go/typesense/api/pointer"
+)
+
+const (
+	UsersCollection = "users"
+	PostsCollection = "posts"
+)
+
+// Users returns the expected schema for the users collection, derived from
+// the User struct.
+func Users() *api.CollectionSchema {
+	return &api.CollectionSchema{
+		Name: UsersCollection,
+		Fields: []api.Field{
+			{Name: "id", Type: "string"},
+			{Name: "username", Type: "string"},
+			{Name: "name", Type: "string"},
+			{Name: "email", Type: "string"},
+			{Name: "bio", Type: "string", Optional: pointer.True()},
+			{Name: "picture", Type: "string", Optional: pointer.True(), Index: pointer.False()},
+			{Name: "school", Type: "string", Facet: pointer.True()},
+			{Name: "country", Type: "string", Facet: pointer.True()},
+			{Name: "campus", Type: "string", Facet: pointer.True()},
+			{Name: "info_updated", Type: "bool"},
+			{Name: "program", Type: "string", Facet: pointer.True()},
+			{Name: "year", Type: "int32", Facet: pointer.True(), Sort: pointer.True()},
+			{Name: "created_at", Type: "int64", Sort: pointer.True()},
+			{Name: "updated_at", Type: "int64", Sort: pointer.True()},
+		},
+		DefaultSortingField: pointer.String("created_at"),
+		TokenSeparators:     &[]string{"-", "_"},
+		SymbolsToIndex:      &[]string{},
+	}
+}
+
+// Posts returns the expected schema for the posts collection, derived from
+// the Post struct.
+func Posts() *api.CollectionSchema {
+	return &api.CollectionSchema{
+		Name: PostsCollection,
+		Fields: []api.Field{
+			{Name: "id", Type: "string"},
+			{Name: "user_id", Type: "string"},
+			{Name: "user_name", Type: "string"},
+			{Name: "user_picture", Type: "string", Optional: pointer.True(), Index: pointer.False()},
+			{Name: "user_bio", Type: "string", Optional: pointer.True(), Index: pointer.False()},
+			{Name: "user_programme", Type: "string", Facet: pointer.True()},
+			{Name: "user_year", Type: "int32", Facet: pointer.True()},
+			{Name: "user_campus", Type: "string", Facet: pointer.True()},
+			{Name: "subject", Type: "string", Facet: pointer.True()},
+			{Name: "title", Type: "string"},
+			{Name: "content", Type: "string"},
+			{Name: "images", Type: "string[]", Optional: pointer.True(), Index: pointer.False()},
+			{Name: "created_at", Type: "int64", Sort: pointer.True()},
+			{Name: "updated_at", Type: "int64", Sort: pointer.True()},
+		},
+		DefaultSortingField: pointer.String("created_at"),
+		TokenSeparators:     &[]string{"-", "_"},
+		SymbolsToIndex:      &[]string{},
+	}
+}
+
+// EnsureResult reports what Ensure did so the caller can decide whether a
+// reindex + alias swap is needed.
+type EnsureResult struct {
+	// Created is true if the collection did not exist and was created as-is.
+	Created bool
+	// Drifted is true if the collection existed but its fields no longer
+	// match the desired schema.
+	Drifted bool
+	// VersionedName is set when Drifted is true and a new, versioned
+	// collection has been created for a reindex-then-alias-swap migration.
+	VersionedName string
+}
+
+// Ensure makes sure the collection described by desired exists against an
+// alias of the same name. If the alias is missing entirely, a fresh
+// collection is created as version 1. If the alias already points at a
+// real collection that has drifted from desired, a new versioned
+// collection is created and returned so the caller can reindex into it
+// and then flip the alias with PromoteAlias.
+//
+// desired.Name is always an alias once createAliased has run once, never a
+// collection in its own right, so this checks the alias (not
+// client.Collection(desired.Name), which is a distinct Typesense resource
+// and would 404 even when the alias and its target collection both exist).
+func Ensure(ctx context.Context, client *typesense.Client, desired *api.CollectionSchema) (EnsureResult, error) {
+	target, err := aliasTarget(ctx, client, desired.Name)
+	if err != nil {
+		return EnsureResult{}, err
+	}
+
+	if target == "" {
+		if err := createAliased(ctx, client, desired, 1); err != nil {
+			return EnsureResult{}, err
+		}
+		return EnsureResult{Created: true}, nil
+	}
+
+	existing, err := client.Collection(target).Retrieve(ctx)
+	if err != nil {
+		return EnsureResult{}, fmt.Errorf("retrieve collection %s: %w", target, err)
+	}
+
+	if !drifted(existing, desired) {
+		return EnsureResult{}, nil
+	}
+
+	versioned := fmt.Sprintf("%s_v%d", desired.Name, nextVersion(target))
+	cloned := *desired
+	cloned.Name = versioned
+	if _, err := client.Collections().Create(ctx, &cloned); err != nil {
+		return EnsureResult{}, fmt.Errorf("create versioned collection %s: %w", versioned, err)
+	}
+
+	return EnsureResult{Drifted: true, VersionedName: versioned}, nil
+}
+
+// aliasTarget returns the real collection name desired.Name's alias
+// currently points at, or "" if the alias doesn't exist yet (i.e. the
+// collection has never been provisioned through the alias-swap scheme).
+func aliasTarget(ctx context.Context, client *typesense.Client, alias string) (string, error) {
+	got, err := client.Alias(alias).Retrieve(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("retrieve alias %s: %w", alias, err)
+	}
+	return got.CollectionName, nil
+}
+
+// Migrate unconditionally creates a new versioned collection for desired,
+// regardless of whether the live schema has drifted. It's used to back the
+// --force-reindex flag, which lets operators trigger a rebuild on demand.
+func Migrate(ctx context.Context, client *typesense.Client, desired *api.CollectionSchema) (string, error) {
+	version := 2
+	if target, err := aliasTarget(ctx, client, desired.Name); err == nil && target != "" {
+		version = nextVersion(target)
+	}
+
+	versioned := fmt.Sprintf("%s_v%d", desired.Name, version)
+	cloned := *desired
+	cloned.Name = versioned
+	if _, err := client.Collections().Create(ctx, &cloned); err != nil {
+		return "", fmt.Errorf("create versioned collection %s: %w", versioned, err)
+	}
+	return versioned, nil
+}
+
+// PromoteAlias points the collection's alias at versionedName once it has
+// been fully reindexed, completing a zero-downtime migration.
+func PromoteAlias(ctx context.Context, client *typesense.Client, collection, versionedName string) error {
+	_, err := client.Aliases().Upsert(ctx, collection, &api.CollectionAliasSchema{
+		CollectionName: versionedName,
+	})
+	if err != nil {
+		return fmt.Errorf("promote alias %s -> %s: %w", collection, versionedName, err)
+	}
+	return nil
+}
+
+func createAliased(ctx context.Context, client *typesense.Client, desired *api.CollectionSchema, version int) error {
+	versioned := *desired
+	versioned.Name = fmt.Sprintf("%s_v%d", desired.Name, version)
+	if _, err := client.Collections().Create(ctx, &versioned); err != nil {
+		return fmt.Errorf("create collection %s: %w", versioned.Name, err)
+	}
+	if err := PromoteAlias(ctx, client, desired.Name, versioned.Name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// drifted does a shallow comparison of field names and types between the
+// live collection and the desired schema.
+func drifted(existing *api.CollectionResponse, desired *api.CollectionSchema) bool {
+	if len(existing.Fields) != len(desired.Fields) {
+		return true
+	}
+	have := make(map[string]string, len(existing.Fields))
+	for _, f := range existing.Fields {
+		have[f.Name] = f.Type
+	}
+	for _, f := range desired.Fields {
+		if have[f.Name] != f.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// nextVersion extracts the trailing "_vN" from a collection name (as
+// assigned by createAliased) and returns N+1, defaulting to 2 for
+// collections that predate versioning.
+func nextVersion(name string) int {
+	idx := strings.LastIndex(name, "_v")
+	if idx == -1 {
+		return 2
+	}
+	var n int
+	if _, err := fmt.Sscanf(name[idx:], "_v%d", &n); err != nil {
+		return 2
+	}
+	return n + 1
+}
+
+func isNotFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "404")
+}
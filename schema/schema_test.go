@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/typesense/typesense-go/typesense/api"
+)
+
+func TestDrifted(t *testing.T) {
+	desired := &api.CollectionSchema{
+		Fields: []api.Field{
+			{Name: "id", Type: "string"},
+			{Name: "year", Type: "int32"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		existing *api.CollectionResponse
+		want     bool
+	}{
+		{
+			name: "matching schema is not drifted",
+			existing: &api.CollectionResponse{
+				Fields: []api.Field{
+					{Name: "id", Type: "string"},
+					{Name: "year", Type: "int32"},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "matching schema in a different field order is not drifted",
+			existing: &api.CollectionResponse{
+				Fields: []api.Field{
+					{Name: "year", Type: "int32"},
+					{Name: "id", Type: "string"},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "missing field is drifted",
+			existing: &api.CollectionResponse{
+				Fields: []api.Field{
+					{Name: "id", Type: "string"},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "changed field type is drifted",
+			existing: &api.CollectionResponse{
+				Fields: []api.Field{
+					{Name: "id", Type: "string"},
+					{Name: "year", Type: "string"},
+				},
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := drifted(tt.existing, desired); got != tt.want {
+				t.Errorf("drifted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// sseFilterKeys are the query params clients can use to scope the events
+// they receive; anything else on the query string is ignored.
+var sseFilterKeys = []string{"user_id", "campus"}
+
+// handleSubscribe returns an SSE handler for the given entity. Clients
+// optionally pass ?since=<stream-sequence> to first replay everything
+// published after that sequence before switching to live tailing, and
+// ?user_id=/?campus= to scope events server-side.
+func (s *SubscriberService) handleSubscribe(e entity) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		filter := map[string]string{}
+		for _, key := range sseFilterKeys {
+			if v := r.URL.Query().Get(key); v != "" {
+				filter[key] = v
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
+		// Subscribe to the hub before replaying so there's no gap between
+		// the replay finishing and live tailing starting; any event
+		// published during the replay is buffered on client.events instead
+		// of being missed.
+		h := s.hubFor(e)
+		client := h.subscribe(filter)
+		defer h.unsubscribe(client)
+
+		if since := r.URL.Query().Get("since"); since != "" {
+			seq, err := strconv.ParseUint(since, 10, 64)
+			if err != nil {
+				http.Error(w, "since must be a stream sequence number", http.StatusBadRequest)
+				return
+			}
+			if err := s.replaySince(e, seq, filter, w, flusher); err != nil {
+				log.Printf("❌ Failed to replay since %d for %s subscribers: %v", seq, e, err)
+			}
+		}
+
+		for {
+			select {
+			case event, ok := <-client.events:
+				if !ok {
+					return
+				}
+				if err := writeSSEEvent(w, event); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// replaySince writes every event published after seq directly to the
+// response before the handler switches the client over to live tailing,
+// including deletes - a reconnecting client must learn about documents
+// removed while it was offline, not just ones created or updated, or it
+// keeps showing content the backend has already removed. A replayed delete
+// carries only the document id, so a client that passes a user_id or campus
+// filter receives no delete events.
+func (s *SubscriberService) replaySince(e entity, seq uint64, filter map[string]string, w http.ResponseWriter, flusher http.Flusher) error {
+	sub, err := s.js.SubscribeSync(subjectPullAll(e), nats.BindStream(streamFor(e)), nats.StartSequence(seq+1))
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		msg, err := sub.NextMsg(500 * time.Millisecond)
+		if err != nil {
+			if err == nats.ErrTimeout {
+				return nil
+			}
+			return err
+		}
+
+		if strings.HasSuffix(msg.Subject, ".delete") {
+			id, err := decodeDeleteID(msg.Data)
+			if err != nil {
+				continue
+			}
+			data := map[string]interface{}{"id": id}
+			if !matches(filter, data) {
+				continue
+			}
+			if err := writeSSEEvent(w, indexEvent{Type: "delete", Data: data}); err != nil {
+				return err
+			}
+			flusher.Flush()
+			continue
+		}
+
+		doc, err := s.decodeDocument(e, msg.Data)
+		if err != nil {
+			continue
+		}
+		if !matches(filter, doc) {
+			continue
+		}
+		if err := writeSSEEvent(w, indexEvent{Type: "update", Data: doc}); err != nil {
+			return err
+		}
+		flusher.Flush()
+	}
+}
+
+func (s *SubscriberService) hubFor(e entity) *hub {
+	if e == entityUser {
+		return s.usersHub
+	}
+	return s.postsHub
+}
+
+func writeSSEEvent(w http.ResponseWriter, event indexEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", payload)
+	return err
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// nakBackoff is how long we ask the server to wait before redelivering a
+// message that failed for a transient reason.
+const nakBackoff = 5 * time.Second
+
+func (s *SubscriberService) subscribeToUserUpserts() {
+	_, err := s.js.QueueSubscribe(subjectUpsert(entityUser), queueGroupName(entityUser, "upsert"), func(msg *nats.Msg) {
+		log.Println("📥 User upsert")
+
+		var user User
+		if err := json.Unmarshal(msg.Data, &user); err != nil {
+			log.Printf("❌ Failed to unmarshal user, terminating message: %v", err)
+			msg.Term()
+			return
+		}
+
+		s.usersBatcher.enqueueUpsert(user.Id, s.userToDocument(user), msg)
+	}, nats.Durable(durableName(entityUser, "upsert")), nats.ManualAck(), nats.AckExplicit())
+
+	if err != nil {
+		log.Fatalf("Failed to subscribe to %s: %v", subjectUpsert(entityUser), err)
+	}
+}
+
+func (s *SubscriberService) subscribeToUserDeleted() {
+	_, err := s.js.QueueSubscribe(subjectDelete(entityUser), queueGroupName(entityUser, "delete"), func(msg *nats.Msg) {
+		log.Println("📥 User deleted")
+
+		// For deletes, we might just get the ID
+		var deleteEvent struct {
+			Id string `json:"id"`
+		}
+		if err := json.Unmarshal(msg.Data, &deleteEvent); err != nil {
+			log.Printf("❌ Failed to unmarshal delete event, terminating message: %v", err)
+			msg.Term()
+			return
+		}
+
+		s.usersBatcher.enqueueDelete(deleteEvent.Id, msg)
+	}, nats.Durable(durableName(entityUser, "delete")), nats.ManualAck(), nats.AckExplicit())
+
+	if err != nil {
+		log.Fatalf("Failed to subscribe to %s: %v", subjectDelete(entityUser), err)
+	}
+}
+
+func (s *SubscriberService) subscribeToPostUpsert() {
+	_, err := s.js.QueueSubscribe(subjectUpsert(entityPost), queueGroupName(entityPost, "upsert"), func(msg *nats.Msg) {
+		log.Println("📥 Post upserted")
+
+		var post Post
+		if err := json.Unmarshal(msg.Data, &post); err != nil {
+			log.Printf("❌ Failed to unmarshal post, terminating message: %v", err)
+			msg.Term()
+			return
+		}
+
+		s.postsBatcher.enqueueUpsert(post.Id, s.postToDocument(post), msg)
+	}, nats.Durable(durableName(entityPost, "upsert")), nats.ManualAck(), nats.AckExplicit())
+
+	if err != nil {
+		log.Fatalf("Failed to subscribe to %s: %v", subjectUpsert(entityPost), err)
+	}
+}
+
+func (s *SubscriberService) subscribeToPostDeleted() {
+	_, err := s.js.QueueSubscribe(subjectDelete(entityPost), queueGroupName(entityPost, "delete"), func(msg *nats.Msg) {
+		log.Println("📥 Post deleted")
+
+		var deleteEvent struct {
+			Id string `json:"id"`
+		}
+		if err := json.Unmarshal(msg.Data, &deleteEvent); err != nil {
+			log.Printf("❌ Failed to unmarshal delete event, terminating message: %v", err)
+			msg.Term()
+			return
+		}
+
+		s.postsBatcher.enqueueDelete(deleteEvent.Id, msg)
+	}, nats.Durable(durableName(entityPost, "delete")), nats.ManualAck(), nats.AckExplicit())
+
+	if err != nil {
+		log.Fatalf("Failed to subscribe to %s: %v", subjectDelete(entityPost), err)
+	}
+}
+
+// drainReplay reads every message currently available on sub and reindexes
+// it, stopping once no message arrives within the timeout (i.e. the stream
+// has been fully drained).
+func (s *SubscriberService) drainReplay(e entity, sub *nats.Subscription) error {
+	defer sub.Unsubscribe()
+
+	count := 0
+	for {
+		msg, err := sub.NextMsg(2 * time.Second)
+		if err != nil {
+			if errors.Is(err, nats.ErrTimeout) {
+				log.Printf("🔁 replay of %s complete: %d messages reindexed", e, count)
+				return nil
+			}
+			return err
+		}
+
+		if err := s.reindex(e, msg); err != nil {
+			log.Printf("❌ Failed to reindex %s during replay: %v", e, err)
+			continue
+		}
+		count++
+	}
+}
+
+// reindex applies a single replayed message to the live index: an upsert
+// for messages on the pull .upsert subject, a delete for messages on the
+// pull .delete subject, so a document removed after its last upsert isn't
+// resurrected by the rebuild.
+func (s *SubscriberService) reindex(e entity, msg *nats.Msg) error {
+	ctx := context.Background()
+
+	if strings.HasSuffix(msg.Subject, ".delete") {
+		id, err := decodeDeleteID(msg.Data)
+		if err != nil {
+			return err
+		}
+		switch e {
+		case entityUser:
+			return s.idx.DeleteUser(ctx, id)
+		case entityPost:
+			return s.idx.DeletePost(ctx, id)
+		default:
+			return nil
+		}
+	}
+
+	doc, err := s.decodeDocument(e, msg.Data)
+	if err != nil {
+		return err
+	}
+	switch e {
+	case entityUser:
+		return s.idx.UpsertUser(ctx, doc)
+	case entityPost:
+		return s.idx.UpsertPost(ctx, doc)
+	default:
+		return nil
+	}
+}
+
+func decodeDeleteID(data []byte) (string, error) {
+	var deleteEvent struct {
+		Id string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &deleteEvent); err != nil {
+		return "", err
+	}
+	return deleteEvent.Id, nil
+}